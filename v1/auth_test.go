@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWTAuthenticatorAcceptsValidToken(t *testing.T) {
+	secret := []byte("test-secret")
+	a := NewJWTAuthenticator(secret)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	signed, err := token.SignedString(secret)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/?token="+signed, nil)
+
+	userID, claims, err := a.Authenticate(r)
+	if err != nil {
+		t.Fatalf("authenticate: %v", err)
+	}
+
+	if userID != "alice" {
+		t.Fatalf("userID = %q, want %q", userID, "alice")
+	}
+
+	if claims["sub"] != "alice" {
+		t.Fatalf("claims[sub] = %v, want alice", claims["sub"])
+	}
+}
+
+func TestJWTAuthenticatorRejectsAlgNone(t *testing.T) {
+	a := NewJWTAuthenticator([]byte("test-secret"))
+
+	token := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
+		"sub": "alice",
+	})
+	signed, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/?token="+signed, nil)
+
+	if _, _, err := a.Authenticate(r); err == nil {
+		t.Fatal("authenticate succeeded for an alg=none token")
+	}
+}
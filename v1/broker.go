@@ -0,0 +1,105 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// Broker fans Messages out across process boundaries so that two clients
+// connected to different instances can still reach each other. A single
+// process subscribes once and re-hashes incoming Receivers into its local
+// UserPools; see WebsocketMgr.brokerSubscriber.
+type Broker interface {
+	// Publish makes message visible to every Subscribe call across the
+	// cluster, including, for simplicity, the publishing process itself.
+	Publish(ctx context.Context, message *Message) error
+
+	// Subscribe returns a channel of every Message published cluster-wide.
+	// The channel is closed when ctx is done.
+	Subscribe(ctx context.Context) (<-chan *Message, error)
+}
+
+// brokerChanSize bounds how many messages may be queued between Publish and
+// a slow Subscribe consumer before further publishes observe backpressure.
+const brokerChanSize = 256
+
+// LocalBroker is a Broker that never leaves the process. It preserves the
+// original single-node fan-out behavior and is the default, so existing
+// deployments and tests see no change until a cluster-aware Broker is
+// configured. All Subscribe channels share one queue, so each published
+// message reaches exactly one of them; with more than one subscriber, no
+// single subscriber sees every message.
+type LocalBroker struct {
+	messageCh chan *Message
+}
+
+// NewLocalBroker creates a Broker scoped to this process only.
+func NewLocalBroker() *LocalBroker {
+	return &LocalBroker{messageCh: make(chan *Message, brokerChanSize)}
+}
+
+func (b *LocalBroker) Publish(ctx context.Context, message *Message) error {
+	select {
+	case b.messageCh <- message:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *LocalBroker) Subscribe(ctx context.Context) (<-chan *Message, error) {
+	out := make(chan *Message, brokerChanSize)
+
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case message := <-b.messageCh:
+				select {
+				case out <- message:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// decodeBrokerMessage unmarshals a Broker payload, logging and returning
+// false on failure so callers can skip the message rather than panic on a
+// malformed cross-node payload.
+func decodeBrokerMessage(data []byte) (*Message, bool) {
+	message := &Message{}
+	if err := json.Unmarshal(data, message); err != nil {
+		wsMgr.log.Error("decode broker message", slog.String("err", err.Error()))
+		return nil, false
+	}
+
+	return message, true
+}
+
+// encodeBrokerMessage marshals message for transport over a cross-node
+// Broker.
+func encodeBrokerMessage(message *Message) ([]byte, error) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("encode broker message: %w", err)
+	}
+
+	return data, nil
+}
+
+// ConfigureBroker overrides the Broker used to fan messages out across
+// processes. The default is a single-process LocalBroker; clustered
+// deployments should call this with NewRedisBroker or NewNATSBroker before
+// handling any connections.
+func ConfigureBroker(b Broker) {
+	wsMgr.broker = b
+}
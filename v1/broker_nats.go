@@ -0,0 +1,83 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsPollInterval bounds how long each NextMsg call blocks, so the
+// subscriber goroutine can notice ctx.Done() promptly instead of only
+// between deliveries.
+const natsPollInterval = time.Second
+
+// NATSBroker is a Broker backed by a NATS subject, for clusters that already
+// run NATS.
+type NATSBroker struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATSBroker returns a Broker that publishes and subscribes on subject
+// using conn.
+func NewNATSBroker(conn *nats.Conn, subject string) *NATSBroker {
+	return &NATSBroker{conn: conn, subject: subject}
+}
+
+func (b *NATSBroker) Publish(_ context.Context, message *Message) error {
+	data, err := encodeBrokerMessage(message)
+	if err != nil {
+		return err
+	}
+
+	if err := b.conn.Publish(b.subject, data); err != nil {
+		return fmt.Errorf("publish to nats: %w", err)
+	}
+
+	return nil
+}
+
+// Subscribe uses a synchronous NATS subscription polled from a single
+// goroutine, the same owns-both-reads-and-close shape broker_redis.go uses.
+// An async nats.Subscribe callback can still be invoked by nats.go's
+// internal delivery goroutine after Unsubscribe returns, which would race
+// that goroutine's close(out) against this one's send; polling NextMsg from
+// the goroutine that also closes out avoids that entirely.
+func (b *NATSBroker) Subscribe(ctx context.Context) (<-chan *Message, error) {
+	sub, err := b.conn.SubscribeSync(b.subject)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe to nats: %w", err)
+	}
+
+	out := make(chan *Message, brokerChanSize)
+
+	go func() {
+		defer close(out)
+		defer sub.Unsubscribe()
+
+		for {
+			msg, err := sub.NextMsg(natsPollInterval)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				continue
+			}
+
+			message, ok := decodeBrokerMessage(msg.Data)
+			if !ok {
+				continue
+			}
+
+			select {
+			case out <- message:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
@@ -0,0 +1,71 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBroker is a Broker backed by Redis Pub/Sub, for clusters that already
+// run Redis.
+type RedisBroker struct {
+	client  *redis.Client
+	channel string
+}
+
+// NewRedisBroker returns a Broker that publishes and subscribes on channel
+// using client.
+func NewRedisBroker(client *redis.Client, channel string) *RedisBroker {
+	return &RedisBroker{client: client, channel: channel}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, message *Message) error {
+	data, err := encodeBrokerMessage(message)
+	if err != nil {
+		return err
+	}
+
+	if err := b.client.Publish(ctx, b.channel, data).Err(); err != nil {
+		return fmt.Errorf("publish to redis: %w", err)
+	}
+
+	return nil
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context) (<-chan *Message, error) {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+
+	out := make(chan *Message, brokerChanSize)
+
+	go func() {
+		defer close(out)
+		defer pubsub.Close()
+
+		ch := pubsub.Channel()
+
+		for {
+			select {
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				message, ok := decodeBrokerMessage([]byte(msg.Payload))
+				if !ok {
+					continue
+				}
+
+				select {
+				case out <- message:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
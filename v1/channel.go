@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ChannelHub manages named pub/sub topics that clients join with
+// "subscribe"/"unsubscribe" control frames, as an alternative to the direct,
+// Receivers-addressed delivery in UserPool.
+type ChannelHub struct {
+	channels sync.Map // channel name -> *Channel
+}
+
+// Channel is a single named topic and its current subscribers.
+type Channel struct {
+	name        string
+	messageCh   chan *Message // Channel for publish frames awaiting fan-out.
+	subscribers sync.Map      // subscriberKey -> *Subscriber
+}
+
+// Subscriber is one device's subscription to a Channel.
+type Subscriber struct {
+	userID string
+	device string
+	conn   *deviceConn
+}
+
+// NewChannelHub creates an empty ChannelHub.
+func NewChannelHub() *ChannelHub {
+	return &ChannelHub{}
+}
+
+// Channels returns the ChannelHub backing HandleWebSocket's
+// "subscribe"/"publish" control frames, so admin code can call its Range
+// method for introspection.
+func Channels() *ChannelHub {
+	return wsMgr.channels
+}
+
+func subscriberKey(userID, device string) string {
+	return userID + "\x00" + device
+}
+
+// getOrCreateChannel returns the named Channel, creating it and starting its
+// fan-out goroutine on first use.
+func (hub *ChannelHub) getOrCreateChannel(name string) *Channel {
+	if v, ok := hub.channels.Load(name); ok {
+		return v.(*Channel)
+	}
+
+	channel := &Channel{name: name, messageCh: make(chan *Message, 64)}
+
+	actual, loaded := hub.channels.LoadOrStore(name, channel)
+	if !loaded {
+		go hub.fanOut(actual.(*Channel))
+	}
+
+	return actual.(*Channel)
+}
+
+// fanOut delivers every message published to channel to its current
+// subscribers.
+func (hub *ChannelHub) fanOut(channel *Channel) {
+	for message := range channel.messageCh {
+		channel.subscribers.Range(func(_, v any) bool {
+			v.(*Subscriber).conn.enqueue(message)
+			return true
+		})
+	}
+}
+
+// Subscribe joins userID's device to the named channel. It rejects a
+// duplicate subscribe from the same user/device pair.
+func (hub *ChannelHub) Subscribe(name, userID, device string, conn *deviceConn) error {
+	channel := hub.getOrCreateChannel(name)
+
+	sub := &Subscriber{userID: userID, device: device, conn: conn}
+
+	if _, loaded := channel.subscribers.LoadOrStore(subscriberKey(userID, device), sub); loaded {
+		return fmt.Errorf("user %q device %q is already subscribed to channel %q", userID, device, name)
+	}
+
+	return nil
+}
+
+// Unsubscribe removes userID's device from the named channel, if present.
+func (hub *ChannelHub) Unsubscribe(name, userID, device string) {
+	v, ok := hub.channels.Load(name)
+	if !ok {
+		return
+	}
+
+	v.(*Channel).subscribers.Delete(subscriberKey(userID, device))
+}
+
+// Publish enqueues message for fan-out to name's current subscribers.
+func (hub *ChannelHub) Publish(name string, message *Message) error {
+	channel := hub.getOrCreateChannel(name)
+
+	select {
+	case channel.messageCh <- message:
+		return nil
+	default:
+		return fmt.Errorf("channel %q is backed up", name)
+	}
+}
+
+// Range calls f for every channel and its current subscriber count, for
+// admin introspection. Iteration stops early if f returns false.
+func (hub *ChannelHub) Range(f func(name string, subscribers int) bool) {
+	hub.channels.Range(func(k, v any) bool {
+		count := 0
+
+		v.(*Channel).subscribers.Range(func(_, _ any) bool {
+			count++
+			return true
+		})
+
+		return f(k.(string), count)
+	})
+}
@@ -0,0 +1,53 @@
+package v1
+
+import "encoding/json"
+
+// Codec marshals and unmarshals a Message for the wire. The negotiated
+// Codec determines whether a device's frames are sent as WebSocket text or
+// binary frames, letting non-JSON payloads (images, voice notes, protobuf)
+// travel without a base64 detour.
+type Codec interface {
+	// Name is the Sec-WebSocket-Protocol value this Codec is selected by.
+	Name() string
+
+	Marshal(message *Message) ([]byte, error)
+	Unmarshal(data []byte, message *Message) error
+
+	// BinaryFrames reports whether this Codec's frames should be sent as
+	// WebSocket binary frames (true) rather than text frames (false).
+	BinaryFrames() bool
+}
+
+// subprotocols lists every Codec HandleWebSocket negotiates over
+// Sec-WebSocket-Protocol, in preference order.
+var subprotocols = []string{"protobuf", "msgpack", "json"}
+
+// selectCodec returns the Codec matching conn's negotiated subprotocol,
+// defaulting to JSONCodec when the client didn't request one of the others.
+func selectCodec(subprotocol string) Codec {
+	switch subprotocol {
+	case "msgpack":
+		return MsgpackCodec{}
+	case "protobuf":
+		return ProtobufCodec{}
+	default:
+		return JSONCodec{}
+	}
+}
+
+// JSONCodec is the original wire format: text frames of encoding/json.
+// MBytes, being a []byte, is base64-encoded per encoding/json's standard
+// behavior for byte slices.
+type JSONCodec struct{}
+
+func (JSONCodec) Name() string { return "json" }
+
+func (JSONCodec) Marshal(message *Message) ([]byte, error) {
+	return json.Marshal(message)
+}
+
+func (JSONCodec) Unmarshal(data []byte, message *Message) error {
+	return json.Unmarshal(data, message)
+}
+
+func (JSONCodec) BinaryFrames() bool { return false }
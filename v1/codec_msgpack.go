@@ -0,0 +1,19 @@
+package v1
+
+import "github.com/vmihailenco/msgpack/v5"
+
+// MsgpackCodec carries Messages as MessagePack binary frames, avoiding the
+// base64 overhead JSON imposes on MBytes.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Name() string { return "msgpack" }
+
+func (MsgpackCodec) Marshal(message *Message) ([]byte, error) {
+	return msgpack.Marshal(message)
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, message *Message) error {
+	return msgpack.Unmarshal(data, message)
+}
+
+func (MsgpackCodec) BinaryFrames() bool { return true }
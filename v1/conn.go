@@ -0,0 +1,150 @@
+package v1
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait is the time allowed to write a message to the peer.
+	writeWait = 10 * time.Second
+
+	// pongWait is the time allowed to read the next pong message from the
+	// peer.
+	pongWait = 60 * time.Second
+
+	// pingPeriod sends pings to the peer with this period. Must be less
+	// than pongWait.
+	pingPeriod = (pongWait * 9) / 10
+
+	// outboundQueueSize bounds how many messages may be queued for a single
+	// device before it is considered unresponsive.
+	outboundQueueSize = 32
+)
+
+// deviceConn owns a single device's WebSocket connection. Writes never
+// happen inline from the dispatcher: callers enqueue onto send and a
+// dedicated writePump goroutine performs the actual write, so one slow
+// device can never block delivery to the rest of a pool or channel.
+type deviceConn struct {
+	conn      *websocket.Conn
+	codec     Codec // Negotiated via Sec-WebSocket-Protocol; determines wire format and frame type.
+	send      chan *Message
+	done      chan struct{} // Closed by close() to signal shutdown; send is never closed, so enqueue can never race a send against a close.
+	closeOnce sync.Once
+}
+
+// newDeviceConn wraps conn, configures its read deadlines/pong handling, and
+// starts its writer goroutine. Outbound messages are encoded with codec.
+func newDeviceConn(conn *websocket.Conn, codec Codec) *deviceConn {
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	dc := &deviceConn{
+		conn:  conn,
+		codec: codec,
+		send:  make(chan *Message, outboundQueueSize),
+		done:  make(chan struct{}),
+	}
+
+	go dc.writePump()
+
+	return dc
+}
+
+// enqueue queues message for delivery without blocking. If the outbound
+// queue is full, or the device has already been closed, the device is
+// treated as unresponsive and disconnected rather than stalling the caller.
+func (dc *deviceConn) enqueue(message *Message) bool {
+	select {
+	case <-dc.done:
+		return false
+	default:
+	}
+
+	select {
+	case dc.send <- message:
+		return true
+	case <-dc.done:
+		return false
+	default:
+		wsMgr.log.Error("outbound queue full, dropping connection", slog.String("remote", dc.conn.RemoteAddr().String()))
+		dc.close()
+		return false
+	}
+}
+
+// enqueueReplay delivers message for offline-backlog replay, blocking until
+// there is room in the outbound queue instead of dropping the connection
+// like enqueue does. replayOfflineMessages can push an arbitrarily large
+// backlog through a device that isn't registered for live delivery yet, so
+// there's no caller to apply backpressure to except the replay loop itself.
+func (dc *deviceConn) enqueueReplay(message *Message) bool {
+	select {
+	case dc.send <- message:
+		return true
+	case <-dc.done:
+		return false
+	}
+}
+
+// close signals the writer goroutine to shut down and close the underlying
+// connection. It is safe to call more than once and safe to call
+// concurrently with enqueue.
+func (dc *deviceConn) close() {
+	dc.closeOnce.Do(func() {
+		close(dc.done)
+	})
+}
+
+// writePump is the only goroutine that writes to conn. It drains send and
+// sends periodic pings, applying a write deadline to every write so a dead
+// peer is detected and the connection torn down instead of left to block
+// forever.
+func (dc *deviceConn) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+
+	defer func() {
+		ticker.Stop()
+		dc.conn.Close()
+	}()
+
+	for {
+		select {
+		case message := <-dc.send:
+			dc.conn.SetWriteDeadline(time.Now().Add(writeWait))
+
+			data, err := dc.codec.Marshal(message)
+			if err != nil {
+				wsMgr.log.Error("encode message", slog.String("err", err.Error()))
+				continue
+			}
+
+			frameType := websocket.TextMessage
+			if dc.codec.BinaryFrames() {
+				frameType = websocket.BinaryMessage
+			}
+
+			if err := dc.conn.WriteMessage(frameType, data); err != nil {
+				wsMgr.log.Error("write to websocket", slog.String("err", err.Error()))
+				return
+			}
+		case <-dc.done:
+			dc.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			dc.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			return
+		case <-ticker.C:
+			dc.conn.SetWriteDeadline(time.Now().Add(writeWait))
+
+			if err := dc.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
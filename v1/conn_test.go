@@ -0,0 +1,97 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestDeviceConn dials a local server that reads and discards every frame,
+// and wraps the client side in a
+// deviceConn, so tests exercise the real *websocket.Conn rather than a fake.
+func newTestDeviceConn(t *testing.T) *deviceConn {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return newDeviceConn(conn, JSONCodec{})
+}
+
+// TestDeviceConnEnqueueCloseRace reproduces concurrent enqueue/close calls
+// under -race: enqueue must never send on a closed channel.
+func TestDeviceConnEnqueueCloseRace(t *testing.T) {
+	dc := newTestDeviceConn(t)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dc.enqueue(&Message{Sender: "alice"})
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		dc.close()
+	}()
+
+	wg.Wait()
+}
+
+// TestDeviceConnEnqueueAfterClose asserts enqueue reports failure instead of
+// panicking once the device has been closed.
+func TestDeviceConnEnqueueAfterClose(t *testing.T) {
+	dc := newTestDeviceConn(t)
+
+	dc.close()
+
+	if dc.enqueue(&Message{Sender: "alice"}) {
+		t.Fatal("enqueue succeeded on a closed deviceConn")
+	}
+}
+
+// TestDeviceConnEnqueueReplayBeyondQueueCap asserts a backlog larger than
+// outboundQueueSize is delivered by blocking for room rather than dropping
+// the connection, unlike enqueue.
+func TestDeviceConnEnqueueReplayBeyondQueueCap(t *testing.T) {
+	dc := newTestDeviceConn(t)
+
+	for i := 0; i < outboundQueueSize+5; i++ {
+		if !dc.enqueueReplay(&Message{Sender: "alice"}) {
+			t.Fatalf("enqueueReplay failed on message %d", i)
+		}
+	}
+
+	select {
+	case <-dc.done:
+		t.Fatal("deviceConn was closed during replay")
+	default:
+	}
+}
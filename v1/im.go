@@ -1,26 +1,45 @@
 package v1
 
 import (
+	"compress/flate"
+	"context"
 	"encoding/json"
 	"hash/fnv"
 	"log/slog"
 	"net/http"
 	"runtime"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
 )
 
 // WebsocketMgr manages WebSocket connections and messages.
 type WebsocketMgr struct {
-	log        *slog.Logger  // Logger for handling logs.
-	users      []*UserPool   // Slice of user pools, each corresponding to a specific set of users.
-	messageCh  chan *Message // Channel for sending and receiving messages.
-	upgrader   websocket.Upgrader
-	messageBuf sync.Pool // Pool for managing reusable Message objects.
-	done       chan struct{}
+	log           *slog.Logger  // Logger for handling logs.
+	users         []*UserPool   // Slice of user pools, each corresponding to a specific set of users.
+	messageCh     chan *Message // Channel for sending and receiving messages.
+	channels      *ChannelHub   // Named pub/sub topics, separate from direct user-to-user delivery.
+	broker        Broker        // Fans messages out across processes; defaults to a single-process LocalBroker.
+	upgrader      websocket.Upgrader
+	messageBuf    sync.Pool     // Pool for managing reusable Message objects.
+	messageStore  MessageStore  // Stores messages for users with no live device, for later replay.
+	authenticator Authenticator // Identifies the caller before the WebSocket upgrade; defaults to DevAuthenticator.
+	acl           ACL           // Restricts who can DM whom; defaults to AllowAllACL.
+	done          chan struct{}
 }
 
+// publishRateLimit governs how often a single connection may send a
+// "publish" control frame, to keep one chatty client from starving the
+// dispatcher for everyone else.
+var publishRateLimit = struct {
+	every rate.Limit
+	burst int
+}{every: rate.Every(100 * time.Millisecond), burst: 8}
+
 // UserPool represents a pool of WebSocket users.
 type UserPool struct {
 	users     sync.Map      // Atomic value to store user connections.
@@ -37,18 +56,53 @@ type Message struct {
 	At        int64           // Timestamp of the message.
 	Device    string          // Device identifier.
 	Sender    string          // Sender's user ID.
-	MBytes    json.RawMessage // Message content as json.
+	MBytes    []byte          // Message content; opaque payload, e.g. an image, voice note, or protobuf-encoded body.
 	Receivers []string        // User IDs of message receivers.
+	Type      string          `json:"type,omitempty"`     // Control frame type: "ack", "subscribe", "unsubscribe", "publish". Empty means a normal chat message.
+	Seq       uint64          `json:"seq,omitempty"`      // Sequence number: set by the MessageStore on replayed messages, echoed back in "ack" frames.
+	Channel   string          `json:"channel,omitempty"`  // Channel name for "subscribe"/"unsubscribe"/"publish" frames.
+	Body      json.RawMessage `json:"body,omitempty"`     // Payload for a "publish" frame.
+	ID        *uuid.UUID      `json:"id,omitempty"`       // Correlation ID, assigned lazily by Reply.
+	ReplyID   *uuid.UUID      `json:"reply_id,omitempty"` // Set on a reply to correlate it back to the Message it answers.
+}
+
+// Reply mirrors m's ID into reply's ReplyID so the recipient can correlate
+// reply back to m, assigning each an ID if it doesn't already have one. This
+// unlocks RPC-style flows (read receipts, typing acks) over the existing
+// message channel.
+func (m *Message) Reply(reply *Message) {
+	if m.ID == nil {
+		id := uuid.New()
+		m.ID = &id
+	}
+
+	reply.ReplyID = m.ID
+
+	if reply.ID == nil {
+		id := uuid.New()
+		reply.ID = &id
+	}
 }
 
 var (
 	numPools = 2 * runtime.NumCPU() // Number of user pools based on CPU cores.
 	wsMgr    = &WebsocketMgr{
-		log:        slog.Default(),
-		messageCh:  make(chan *Message),
-		upgrader:   websocket.Upgrader{},
-		messageBuf: sync.Pool{New: func() interface{} { return &Message{} }},
-		done:       make(chan struct{}),
+		log:       slog.Default(),
+		messageCh: make(chan *Message),
+		upgrader: websocket.Upgrader{
+			// Explicit dev-permissive default; call ConfigureCheckOrigin to
+			// restrict this in production.
+			CheckOrigin:       func(r *http.Request) bool { return true },
+			Subprotocols:      subprotocols,
+			EnableCompression: true,
+		},
+		messageBuf:    sync.Pool{New: func() interface{} { return &Message{} }},
+		channels:      NewChannelHub(),
+		broker:        NewLocalBroker(),
+		messageStore:  NewMemoryStore(),
+		authenticator: DevAuthenticator{},
+		acl:           AllowAllACL{},
+		done:          make(chan struct{}),
 	}
 )
 
@@ -61,10 +115,44 @@ func init() {
 
 		go wsMgr.messageDispatcher(wsMgr.users[i])
 	}
+
+	go wsMgr.brokerSubscriber()
+}
+
+// brokerSubscriber subscribes to wsMgr.broker for the lifetime of the
+// process and re-hashes every Message it sees into this process's local
+// UserPools, the same way handleConnection used to enqueue directly.
+func (mgr *WebsocketMgr) brokerSubscriber() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-mgr.done
+		cancel()
+	}()
+
+	messages, err := mgr.broker.Subscribe(ctx)
+	if err != nil {
+		mgr.log.Error("broker subscribe", slog.String("err", err.Error()))
+		return
+	}
+
+	for message := range messages {
+		for i := range message.Receivers {
+			mgr.users[hashIndex(message.Receivers[i])].messageCh <- message
+		}
+
+		mgr.users[hashIndex(message.Sender)].messageCh <- message
+	}
 }
 
 // HandleWebSocket handles WebSocket upgrade requests.
 func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	userID, _, err := wsMgr.authenticator.Authenticate(r)
+	if err != nil {
+		wsMgr.log.Error("authenticate", slog.String("err", err.Error()))
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	conn, err := wsMgr.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		wsMgr.log.Error("websocket upgrade", slog.String("err", err.Error()))
@@ -72,12 +160,16 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
-	userID := r.URL.Query().Get("user")
+	conn.SetCompressionLevel(flate.BestSpeed)
+
+	codec := selectCodec(conn.Subprotocol())
+
 	device := r.URL.Query().Get("device")
+	since, _ := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
 
 	userPool := wsMgr.users[hashIndex(userID)]
 
-	userPool.userDeviceJoin(userID, device, conn)
+	userPool.userDeviceJoin(userID, device, conn, codec, since)
 }
 
 // hashIndex calculates the pool index based on the user ID.
@@ -87,13 +179,20 @@ func hashIndex(id string) uint32 {
 	return h.Sum32() % uint32(numPools)
 }
 
-func (pool *UserPool) userDeviceJoin(userID, deviceType string, conn *websocket.Conn) {
+func (pool *UserPool) userDeviceJoin(userID, deviceType string, conn *websocket.Conn, codec Codec, since uint64) {
+	dc := newDeviceConn(conn, codec)
+
+	// Replay the backlog before dc is registered below, so a live message
+	// dispatched the instant this device becomes visible can never overtake
+	// messages that were already queued for delivery.
+	pool.replayOfflineMessages(userID, dc, since)
+
 	pool.users.Range(func(uid, user any) bool {
 		if uid != userID {
 			return true
 		}
 
-		user.(*User).devices.Store(deviceType, conn)
+		user.(*User).devices.Store(deviceType, dc)
 
 		return false
 	})
@@ -106,40 +205,110 @@ func (pool *UserPool) userDeviceJoin(userID, deviceType string, conn *websocket.
 			messageCh: make(chan *Message),
 		}
 
-		u.devices.Store(deviceType, conn)
+		u.devices.Store(deviceType, dc)
 
 		pool.users.Store(userID, u)
 	} else {
 
-		user.(*User).devices.Store(deviceType, conn)
+		user.(*User).devices.Store(deviceType, dc)
 
 		pool.users.Store(userID, user)
 	}
 
-	pool.handleConnection(userID, conn, deviceType)
+	pool.handleConnection(userID, conn, dc, deviceType)
 }
 
-func (pool *UserPool) handleConnection(userID string, conn *websocket.Conn, deviceType string) {
+// replayOfflineMessages delivers every message stored for userID since the
+// client's last_ack cursor, in order. It uses enqueueReplay rather than
+// enqueue: the backlog can be far larger than the live-traffic outbound
+// queue cap, and dc isn't visible to messageDispatcher yet, so there's no
+// harm in blocking here until writePump drains room for each message.
+func (pool *UserPool) replayOfflineMessages(userID string, dc *deviceConn, since uint64) {
+	stored, err := wsMgr.messageStore.Since(userID, since)
+	if err != nil {
+		wsMgr.log.Error("load offline messages", slog.String("err", err.Error()))
+		return
+	}
+
+	for _, sm := range stored {
+		sm.Message.Seq = sm.Seq
+
+		if !dc.enqueueReplay(sm.Message) {
+			return
+		}
+	}
+}
+
+func (pool *UserPool) handleConnection(userID string, conn *websocket.Conn, dc *deviceConn, deviceType string) {
+	subscribed := make(map[string]bool)
+
 	defer func() {
+		for name := range subscribed {
+			wsMgr.channels.Unsubscribe(name, userID, deviceType)
+		}
+
+		dc.close()
 		pool.userDeviceLeft(userID, deviceType)
 	}()
 
-	for {
-		message := wsMgr.getMessageFromPool()
+	publishLimiter := rate.NewLimiter(publishRateLimit.every, publishRateLimit.burst)
 
-		if err := conn.ReadJSON(message); err != nil {
+	for {
+		mt, data, err := conn.ReadMessage()
+		if err != nil {
 			wsMgr.log.Error("read data from websocket", slog.String("err", err.Error()))
 			break
 		}
 
+		if mt != websocket.TextMessage && mt != websocket.BinaryMessage {
+			continue
+		}
+
+		message := wsMgr.getMessageFromPool()
+
+		if err := dc.codec.Unmarshal(data, message); err != nil {
+			wsMgr.log.Error("decode message", slog.String("err", err.Error()))
+			continue
+		}
+
+		switch message.Type {
+		case "ack":
+			if err := wsMgr.messageStore.Ack(userID, message.Seq); err != nil {
+				wsMgr.log.Error("ack offline messages", slog.String("err", err.Error()))
+			}
+			continue
+		case "subscribe":
+			if err := wsMgr.channels.Subscribe(message.Channel, userID, deviceType, dc); err != nil {
+				wsMgr.log.Error("subscribe", slog.String("err", err.Error()))
+			} else {
+				subscribed[message.Channel] = true
+			}
+			continue
+		case "unsubscribe":
+			wsMgr.channels.Unsubscribe(message.Channel, userID, deviceType)
+			delete(subscribed, message.Channel)
+			continue
+		case "publish":
+			if !publishLimiter.Allow() {
+				wsMgr.log.Error("publish rate limit exceeded", slog.String("user", userID), slog.String("channel", message.Channel))
+				continue
+			}
+
+			message.Sender = userID
+			message.Device = deviceType
+
+			if err := wsMgr.channels.Publish(message.Channel, message); err != nil {
+				wsMgr.log.Error("publish", slog.String("err", err.Error()))
+			}
+			continue
+		}
+
 		message.Sender = userID
 		message.Device = deviceType
 
-		for i := range message.Receivers {
-			wsMgr.users[hashIndex(message.Receivers[i])].messageCh <- message
+		if err := wsMgr.broker.Publish(context.Background(), message); err != nil {
+			wsMgr.log.Error("publish to broker", slog.String("err", err.Error()), slog.Any("data", message))
 		}
-
-		wsMgr.users[hashIndex(userID)].messageCh <- message
 	}
 }
 
@@ -166,22 +335,39 @@ func (mgr *WebsocketMgr) messageDispatcher(pool *UserPool) {
 			pool.users.Range(func(uid, user any) bool {
 				for i := 0; i < len(message.Receivers); i++ {
 					if uid == message.Receivers[i] {
+						if !wsMgr.acl.CanSend(message.Sender, uid.(string)) {
+							continue
+						}
+
+						delivered := false
+
 						user.(*User).devices.Range(func(device, conn any) bool {
-							if err := conn.(*websocket.Conn).WriteJSON(message); err != nil {
-								wsMgr.log.Error("dropped message", slog.String("err", err.Error()), slog.Any("data", message))
+							if conn.(*deviceConn).enqueue(message) {
+								delivered = true
 							}
 							return true
 						})
+
+						// No device was online, or every online device's
+						// outbound queue was full and got dropped: either
+						// way the message was never durably delivered, so
+						// fall back to the offline store for replay.
+						if !delivered {
+							if _, err := wsMgr.messageStore.Append(uid.(string), message); err != nil {
+								wsMgr.log.Error("store offline message", slog.String("err", err.Error()), slog.Any("data", message))
+							}
+						}
 					}
 				}
 				if uid == message.Sender {
 					user.(*User).devices.Range(func(device, conn any) bool {
-						if device == message.Device {
+						// A reply is echoed to every one of the sender's devices,
+						// including the device that sent it, so RPC-style flows
+						// (read receipts, typing acks) reach that device too.
+						if device == message.Device && message.ReplyID == nil {
 							return true
 						}
-						if err := conn.(*websocket.Conn).WriteJSON(message); err != nil {
-							wsMgr.log.Error("dropped message", slog.String("err", err.Error()), slog.Any("data", message))
-						}
+						conn.(*deviceConn).enqueue(message)
 						return true
 					})
 				}
@@ -0,0 +1,108 @@
+package v1
+
+import "sync"
+
+// StoredMessage pairs a persisted Message with the sequence number it was
+// assigned by a MessageStore.
+type StoredMessage struct {
+	Seq     uint64   // Monotonically increasing sequence number, scoped to a single user.
+	Message *Message // The message payload as originally dispatched.
+}
+
+// MessageStore persists messages for users who have no live device in any
+// UserPool, so they can be replayed once the user reconnects. Implementations
+// must be safe for concurrent use.
+type MessageStore interface {
+	// Append stores message for userID and returns the sequence number it was
+	// assigned.
+	Append(userID string, message *Message) (seq uint64, err error)
+
+	// Since returns all messages stored for userID with a sequence number
+	// greater than lastAck, ordered oldest first.
+	Since(userID string, lastAck uint64) ([]*StoredMessage, error)
+
+	// Ack advances userID's delivered cursor to seq and prunes any stored
+	// messages at or below it.
+	Ack(userID string, seq uint64) error
+}
+
+// MemoryStore is an in-memory MessageStore. It does not survive a process
+// restart and is mainly useful for tests and single-node deployments that
+// don't need durability across restarts.
+type MemoryStore struct {
+	mu    sync.Mutex
+	users map[string]*memoryUserQueue
+}
+
+type memoryUserQueue struct {
+	nextSeq uint64
+	queue   []*StoredMessage
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{users: make(map[string]*memoryUserQueue)}
+}
+
+func (s *MemoryStore) Append(userID string, message *Message) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.users[userID]
+	if !ok {
+		q = &memoryUserQueue{}
+		s.users[userID] = q
+	}
+
+	q.nextSeq++
+	q.queue = append(q.queue, &StoredMessage{Seq: q.nextSeq, Message: message})
+
+	return q.nextSeq, nil
+}
+
+func (s *MemoryStore) Since(userID string, lastAck uint64) ([]*StoredMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.users[userID]
+	if !ok {
+		return nil, nil
+	}
+
+	out := make([]*StoredMessage, 0, len(q.queue))
+	for _, sm := range q.queue {
+		if sm.Seq > lastAck {
+			out = append(out, sm)
+		}
+	}
+
+	return out, nil
+}
+
+func (s *MemoryStore) Ack(userID string, seq uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.users[userID]
+	if !ok {
+		return nil
+	}
+
+	pruned := q.queue[:0]
+	for _, sm := range q.queue {
+		if sm.Seq > seq {
+			pruned = append(pruned, sm)
+		}
+	}
+	q.queue = pruned
+
+	return nil
+}
+
+// ConfigureMessageStore overrides the MessageStore used to persist messages
+// for offline users. The default is an unbounded MemoryStore; production
+// deployments should call this with a durable implementation such as
+// NewBoltStore or NewSQLiteStore before handling any connections.
+func ConfigureMessageStore(s MessageStore) {
+	wsMgr.messageStore = s
+}
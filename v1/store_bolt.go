@@ -0,0 +1,132 @@
+package v1
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// messagesBucket is the root bucket holding one nested, per-user bucket of
+// seq -> json-encoded Message.
+var messagesBucket = []byte("messages")
+
+// BoltStore is a MessageStore backed by a single BoltDB file, suitable for
+// durable single-node deployments.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (creating if necessary) a BoltDB file at path and
+// returns a MessageStore backed by it.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open bolt store: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(messagesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init bolt store: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Close closes the underlying BoltDB file.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) Append(userID string, message *Message) (uint64, error) {
+	var seq uint64
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		users := tx.Bucket(messagesBucket)
+
+		user, err := users.CreateBucketIfNotExists([]byte(userID))
+		if err != nil {
+			return err
+		}
+
+		seq, err = user.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(message)
+		if err != nil {
+			return err
+		}
+
+		return user.Put(seqKey(seq), data)
+	})
+
+	return seq, err
+}
+
+func (s *BoltStore) Since(userID string, lastAck uint64) ([]*StoredMessage, error) {
+	var out []*StoredMessage
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		users := tx.Bucket(messagesBucket)
+
+		user := users.Bucket([]byte(userID))
+		if user == nil {
+			return nil
+		}
+
+		c := user.Cursor()
+		for k, v := c.Seek(seqKey(lastAck + 1)); k != nil; k, v = c.Next() {
+			message := &Message{}
+			if err := json.Unmarshal(v, message); err != nil {
+				return err
+			}
+
+			out = append(out, &StoredMessage{Seq: binary.BigEndian.Uint64(k), Message: message})
+		}
+
+		return nil
+	})
+
+	return out, err
+}
+
+func (s *BoltStore) Ack(userID string, seq uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		users := tx.Bucket(messagesBucket)
+
+		user := users.Bucket([]byte(userID))
+		if user == nil {
+			return nil
+		}
+
+		var stale [][]byte
+
+		c := user.Cursor()
+		for k, _ := c.First(); k != nil && binary.BigEndian.Uint64(k) <= seq; k, _ = c.Next() {
+			stale = append(stale, append([]byte(nil), k...))
+		}
+
+		for _, k := range stale {
+			if err := user.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// seqKey encodes seq as a big-endian key so BoltDB's byte-ordered cursor
+// iterates sequence numbers in numeric order.
+func seqKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
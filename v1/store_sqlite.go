@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore is a MessageStore backed by a SQLite database, an alternative
+// to BoltStore for deployments that already standardize on SQLite.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (creating if necessary) a SQLite database at path and
+// returns a MessageStore backed by it.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite store: %w", err)
+	}
+
+	// SQLite allows only one writer at a time; UserPool dispatchers call
+	// Append and connection handlers call Ack concurrently, for different
+	// users against the same file, so
+	// without this a second writer fails with SQLITE_BUSY instead of simply
+	// waiting its turn.
+	db.SetMaxOpenConns(1)
+
+	const schema = `
+CREATE TABLE IF NOT EXISTS offline_messages (
+	user_id TEXT NOT NULL,
+	seq     INTEGER NOT NULL,
+	payload BLOB NOT NULL,
+	PRIMARY KEY (user_id, seq)
+)`
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init sqlite store: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// Close closes the underlying SQLite database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) Append(userID string, message *Message) (uint64, error) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var seq uint64
+	row := tx.QueryRow(`SELECT COALESCE(MAX(seq), 0) + 1 FROM offline_messages WHERE user_id = ?`, userID)
+	if err := row.Scan(&seq); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO offline_messages (user_id, seq, payload) VALUES (?, ?, ?)`, userID, seq, data); err != nil {
+		return 0, err
+	}
+
+	return seq, tx.Commit()
+}
+
+func (s *SQLiteStore) Since(userID string, lastAck uint64) ([]*StoredMessage, error) {
+	rows, err := s.db.Query(`SELECT seq, payload FROM offline_messages WHERE user_id = ? AND seq > ? ORDER BY seq`, userID, lastAck)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*StoredMessage
+	for rows.Next() {
+		var seq uint64
+		var payload []byte
+		if err := rows.Scan(&seq, &payload); err != nil {
+			return nil, err
+		}
+
+		message := &Message{}
+		if err := json.Unmarshal(payload, message); err != nil {
+			return nil, err
+		}
+
+		out = append(out, &StoredMessage{Seq: seq, Message: message})
+	}
+
+	return out, rows.Err()
+}
+
+func (s *SQLiteStore) Ack(userID string, seq uint64) error {
+	_, err := s.db.Exec(`DELETE FROM offline_messages WHERE user_id = ? AND seq <= ?`, userID, seq)
+	return err
+}
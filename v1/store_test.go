@@ -0,0 +1,65 @@
+package v1
+
+import "testing"
+
+func TestMemoryStoreSinceOrdersAndFiltersByAck(t *testing.T) {
+	s := NewMemoryStore()
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.Append("alice", &Message{Sender: "bob"}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	stored, err := s.Since("alice", 1)
+	if err != nil {
+		t.Fatalf("since: %v", err)
+	}
+
+	if len(stored) != 2 {
+		t.Fatalf("got %d messages, want 2", len(stored))
+	}
+
+	for i, sm := range stored {
+		wantSeq := uint64(i + 2)
+		if sm.Seq != wantSeq {
+			t.Fatalf("stored[%d].Seq = %d, want %d", i, sm.Seq, wantSeq)
+		}
+	}
+}
+
+func TestMemoryStoreAckPrunesDeliveredMessages(t *testing.T) {
+	s := NewMemoryStore()
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.Append("alice", &Message{Sender: "bob"}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	if err := s.Ack("alice", 2); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+
+	stored, err := s.Since("alice", 0)
+	if err != nil {
+		t.Fatalf("since: %v", err)
+	}
+
+	if len(stored) != 1 || stored[0].Seq != 3 {
+		t.Fatalf("got %v, want a single message with Seq 3", stored)
+	}
+}
+
+func TestMemoryStoreSinceUnknownUser(t *testing.T) {
+	s := NewMemoryStore()
+
+	stored, err := s.Since("nobody", 0)
+	if err != nil {
+		t.Fatalf("since: %v", err)
+	}
+
+	if stored != nil {
+		t.Fatalf("got %v, want nil for an unknown user", stored)
+	}
+}